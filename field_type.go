@@ -1,5 +1,10 @@
 package pgs
 
+import (
+	"strconv"
+	"strings"
+)
+
 // FieldType describes the type of a Field.
 type FieldType interface {
 	// Field returns the parent Field of this type. While two FieldTypes might be
@@ -30,6 +35,14 @@ type FieldType interface {
 	// the field is prefixed as optional.
 	IsOptional() bool
 
+	// HasPresence returns true if the field distinguishes between an unset
+	// and default value. This is always true for singular embedded messages
+	// and singular Proto2 fields, and always false for repeated and map
+	// fields. For other Proto3 fields, it is only true if the field is a
+	// member of a oneof: either a real oneof, or the synthetic oneof of a
+	// field declared with the "optional" prefix (explicit presence).
+	HasPresence() bool
+
 	// IsRequired returns true if and only if the field is prefixed as required.
 	IsRequired() bool
 
@@ -62,6 +75,32 @@ type FieldType interface {
 	// Nil will be return sif IsMap returns false.
 	Key() FieldTypeElem
 
+	// IsExtension returns true if and only if this field is a proto2
+	// extension field.
+	IsExtension() bool
+
+	// Extendee returns the Message that this field extends. If IsExtension
+	// returns false, this value will be nil.
+	Extendee() Message
+
+	// StructTag returns the canonical `protobuf:"..."` struct tag contents
+	// for this field, in the same format emitted by protoc-gen-go and
+	// understood by the protobuf runtime's internal/encoding/tag package.
+	StructTag() string
+
+	// WellKnownType identifies this field as one of the google.protobuf
+	// well-known message types, based on the embedded message's fully
+	// qualified name. For repeated and map fields, the element (map value)
+	// message is used. WKTUnknown is returned for non-message fields and
+	// for embedded messages that are not well-known types.
+	WellKnownType() WellKnownType
+
+	// IsGroup returns true if and only if the field is a proto2 group
+	// (wire type 3/4). Groups are embedded messages declared inline in
+	// their parent rather than as an independent type; their body remains
+	// reachable via Embed.
+	IsGroup() bool
+
 	setField(f Field)
 	toElem() FieldTypeElem
 }
@@ -71,21 +110,25 @@ type scalarT struct {
 	name TypeName
 }
 
-func (s *scalarT) Field() Field           { return s.fld }
-func (s *scalarT) IsRepeated() bool       { return false }
-func (s *scalarT) IsMap() bool            { return false }
-func (s *scalarT) IsEnum() bool           { return false }
-func (s *scalarT) IsEmbed() bool          { return false }
-func (s *scalarT) Name() TypeName         { return s.name }
-func (s *scalarT) IsSlice() bool          { return s.ProtoType().IsSlice() }
-func (s *scalarT) ProtoType() ProtoType   { return ProtoType(s.fld.Descriptor().GetType()) }
-func (s *scalarT) ProtoLabel() ProtoLabel { return ProtoLabel(s.fld.Descriptor().GetLabel()) }
-func (s *scalarT) Imports() []Package     { return nil }
-func (s *scalarT) setField(f Field)       { s.fld = f }
-func (s *scalarT) Enum() Enum             { return nil }
-func (s *scalarT) Embed() Message         { return nil }
-func (s *scalarT) Element() FieldTypeElem { return nil }
-func (s *scalarT) Key() FieldTypeElem     { return nil }
+func (s *scalarT) Field() Field                 { return s.fld }
+func (s *scalarT) IsRepeated() bool             { return false }
+func (s *scalarT) IsMap() bool                  { return false }
+func (s *scalarT) IsEnum() bool                 { return false }
+func (s *scalarT) IsEmbed() bool                { return false }
+func (s *scalarT) Name() TypeName               { return s.name }
+func (s *scalarT) IsSlice() bool                { return s.ProtoType().IsSlice() }
+func (s *scalarT) ProtoType() ProtoType         { return ProtoType(s.fld.Descriptor().GetType()) }
+func (s *scalarT) ProtoLabel() ProtoLabel       { return ProtoLabel(s.fld.Descriptor().GetLabel()) }
+func (s *scalarT) Imports() []Package           { return nil }
+func (s *scalarT) setField(f Field)             { s.fld = f }
+func (s *scalarT) Enum() Enum                   { return nil }
+func (s *scalarT) Embed() Message               { return nil }
+func (s *scalarT) Element() FieldTypeElem       { return nil }
+func (s *scalarT) Key() FieldTypeElem           { return nil }
+func (s *scalarT) IsExtension() bool            { return false }
+func (s *scalarT) Extendee() Message            { return nil }
+func (s *scalarT) WellKnownType() WellKnownType { return WKTUnknown }
+func (s *scalarT) IsGroup() bool                { return false }
 
 func (s *scalarT) IsOptional() bool {
 	return !s.fld.Syntax().SupportsRequiredPrefix() || s.ProtoLabel() == Optional
@@ -95,6 +138,46 @@ func (s *scalarT) IsRequired() bool {
 	return s.fld.Syntax().SupportsRequiredPrefix() && s.ProtoLabel() == Required
 }
 
+func (s *scalarT) HasPresence() bool {
+	// OneofIndex is set both for a real "oneof { ... }" member and for the
+	// synthetic oneof Proto3Optional generates; membership in either always
+	// implies explicit presence, so checking it directly covers both cases
+	// without needing to first classify which kind of oneof it is.
+	return s.fld.Syntax().SupportsRequiredPrefix() || s.fld.Descriptor().OneofIndex != nil
+}
+
+func (s *scalarT) StructTag() string {
+	return buildStructTag(s, wireTypeTag(s.ProtoType()), s.cardinality(), false, "", s.defaultTag())
+}
+
+func (s *scalarT) cardinality() string {
+	if s.IsRequired() {
+		return "req"
+	}
+	return "opt"
+}
+
+func (s *scalarT) defaultTag() string {
+	raw := s.fld.Descriptor().GetDefaultValue()
+	if raw == "" {
+		return ""
+	}
+
+	switch s.ProtoType() {
+	case BoolT:
+		if raw == "true" {
+			return "1"
+		}
+		return "0"
+	case StringT, BytesT:
+		// The descriptor already carries the string verbatim (or, for
+		// bytes, already C-escaped); neither is quoted or re-escaped here.
+		return raw
+	default:
+		return raw
+	}
+}
+
 func (s *scalarT) toElem() FieldTypeElem {
 	return &scalarE{
 		typ:   s,
@@ -125,13 +208,49 @@ func (e *enumT) toElem() FieldTypeElem {
 	}
 }
 
+func (e *enumT) StructTag() string {
+	return buildStructTag(e, "varint", e.cardinality(), false, e.enum.FullyQualifiedName(), e.defaultTag())
+}
+
+func (e *enumT) defaultTag() string {
+	raw := e.fld.Descriptor().GetDefaultValue()
+	if raw == "" {
+		return ""
+	}
+
+	for _, v := range e.enum.Values() {
+		if v.Name().String() == raw {
+			return strconv.Itoa(int(v.Value()))
+		}
+	}
+
+	return raw
+}
+
 type embedT struct {
 	*scalarT
 	msg Message
 }
 
-func (e *embedT) Embed() Message { return e.msg }
-func (e *embedT) IsEmbed() bool  { return true }
+func (e *embedT) Embed() Message    { return e.msg }
+func (e *embedT) IsEmbed() bool     { return true }
+func (e *embedT) HasPresence() bool { return true }
+
+// StructTag is overridden (rather than inherited from scalarT) so that
+// buildStructTag receives e itself as its FieldType argument rather than
+// the embedded *scalarT. groupOrFieldName only matches *groupT, which
+// overrides StructTag again for that reason, so for a plain embedded
+// message the result is the same as scalarT's.
+func (e *embedT) StructTag() string {
+	return buildStructTag(e, wireTypeTag(e.ProtoType()), e.cardinality(), false, "", e.defaultTag())
+}
+
+func (e *embedT) WellKnownType() WellKnownType {
+	if wkt, ok := wellKnownTypes[e.msg.FullyQualifiedName()]; ok {
+		return wkt
+	}
+	return WKTUnknown
+}
 
 func (e *embedT) Imports() []Package {
 	if pkg := e.msg.Package(); pkg.GoName() != e.fld.Package().GoName() {
@@ -147,6 +266,30 @@ func (e *embedT) toElem() FieldTypeElem {
 	}
 }
 
+// groupT represents a proto2 group field (wire type 3/4). It is encoded
+// like an embedded message, but is declared inline in its parent rather
+// than as an independent type.
+//
+// groupT must be selected over embedT at field-parsing time, where
+// FieldDescriptorProto.Type is inspected; nothing in this file performs
+// that selection, since it is done where FieldType values are first built
+// alongside the rest of the Field (the same place that already chooses
+// among scalarT, enumT, and embedT for every other field).
+type groupT struct {
+	*embedT
+}
+
+func (g *groupT) IsGroup() bool { return true }
+
+// StructTag overrides embedT's, for the same reason embedT overrides
+// scalarT's: without it, method promotion would call embedT.StructTag with
+// the embedded *embedT as the receiver, so groupOrFieldName's *groupT type
+// assertion would never see the actual group and would always fall back to
+// the (wrong, lowercased) field name.
+func (g *groupT) StructTag() string {
+	return buildStructTag(g, wireTypeTag(g.ProtoType()), g.cardinality(), false, "", g.defaultTag())
+}
+
 type repT struct {
 	*scalarT
 	el FieldTypeElem
@@ -156,24 +299,252 @@ func (r *repT) IsRepeated() bool       { return true }
 func (r *repT) Element() FieldTypeElem { return r.el }
 func (r *repT) IsSlice() bool          { return true }
 
+// HasPresence is always false for repeated (and, via mapT embedding repT,
+// map) fields: there is no way to distinguish "unset" from "empty", in
+// Proto2 or Proto3 alike. Without this override, mapT and repT would
+// inherit scalarT.HasPresence, which reports true for every Proto2 field
+// regardless of repetition.
+func (r *repT) HasPresence() bool { return false }
+
 func (r *repT) Imports() []Package { return r.el.Imports() }
 
 func (r *repT) toElem() FieldTypeElem { panic("cannot convert repeated FieldType to FieldTypeElem") }
 
+func (r *repT) WellKnownType() WellKnownType {
+	if msg := r.el.Embed(); msg != nil {
+		if wkt, ok := wellKnownTypes[msg.FullyQualifiedName()]; ok {
+			return wkt
+		}
+	}
+	return WKTUnknown
+}
+
+func (r *repT) StructTag() string {
+	var enum string
+	if e := r.el.Enum(); e != nil {
+		enum = e.FullyQualifiedName()
+	}
+	return buildStructTag(r, wireTypeTag(r.el.ProtoType()), "rep", r.packed(), enum, "")
+}
+
+func (r *repT) packed() bool {
+	if opts := r.fld.Descriptor().GetOptions(); opts != nil && opts.Packed != nil {
+		return opts.GetPacked()
+	}
+	return !r.fld.Syntax().SupportsRequiredPrefix() && isPackable(r.el.ProtoType())
+}
+
+// MapType extends FieldType for fields where IsMap returns true, exposing
+// the synthetic map entry message's key and value as full Fields. The entry
+// message remains hidden from Message.Messages, but its fields are reachable
+// through this interface so callers can reuse the standard Field API (Go
+// type resolution, struct tags, defaults, etc) instead of the more limited
+// FieldTypeElem returned by Key and Element.
+type MapType interface {
+	FieldType
+
+	// MapKeyField returns the Field backing the map entry's key. It is
+	// populated from the synthetic entry message when the map field is
+	// parsed, the same place Key and Element are populated; it is nil on
+	// any mapT that construction step hasn't reached yet.
+	MapKeyField() Field
+
+	// MapValueField returns the Field backing the map entry's value. See
+	// the MapKeyField docs for when it is populated.
+	MapValueField() Field
+}
+
 type mapT struct {
 	*repT
 	key FieldTypeElem
+
+	// keyField and valField back MapKeyField/MapValueField and must be set
+	// from the entry message's own Fields at the same point el and key are
+	// derived; neither is constructed in this file.
+	keyField Field
+	valField Field
+}
+
+func (m *mapT) IsRepeated() bool     { return false }
+func (m *mapT) IsMap() bool          { return true }
+func (m *mapT) IsSlice() bool        { return false }
+func (m *mapT) Key() FieldTypeElem   { return m.key }
+func (m *mapT) MapKeyField() Field   { return m.keyField }
+func (m *mapT) MapValueField() Field { return m.valField }
+
+// StructTag reports the tag for the synthetic map entry field itself.
+func (m *mapT) StructTag() string {
+	return buildStructTag(m, "bytes", "rep", false, "", "")
+}
+
+// extensionT wraps a scalarT, enumT, or embedT to mark it as a proto2
+// extension field, tracking the Message it extends.
+//
+// Wrapping a FieldType in extensionT, resolving Extendee from
+// FieldDescriptorProto.Extendee, and enumerating extension Fields on File
+// and Message are all done where Fields are first built from their
+// descriptors; none of that construction lives in this file.
+type extensionT struct {
+	FieldType
+	extendee Message
+}
+
+func (e *extensionT) IsExtension() bool { return true }
+func (e *extensionT) Extendee() Message { return e.extendee }
+
+// WellKnownType identifies one of the message types defined by Google in
+// the well-known types of the protobuf distribution (wrappers.proto,
+// timestamp.proto, duration.proto, struct.proto, any.proto, etc), as
+// reported by FieldType.WellKnownType.
+//
+// WellKnownType is a lookup only: it does not by itself change Name,
+// Imports, or StructTag for a field. Mapping a WellKnownType to an
+// idiomatic Go type (e.g. WKTTimestamp to *time.Time) is a generation-time
+// decision that belongs to the Module/Context a plugin runs under, not to
+// FieldType, since whether to apply it is opt-in per plugin.
+type WellKnownType int
+
+const (
+	// WKTUnknown indicates a field is not a well-known type, either because
+	// neither it nor (for repeated and map fields) its element is a message,
+	// or its message is not one of the types below.
+	WKTUnknown WellKnownType = iota
+	WKTAny
+	WKTDuration
+	WKTEmpty
+	WKTStruct
+	WKTTimestamp
+	WKTValue
+	WKTListValue
+	WKTDoubleValue
+	WKTFloatValue
+	WKTInt64Value
+	WKTUInt64Value
+	WKTInt32Value
+	WKTUInt32Value
+	WKTBoolValue
+	WKTStringValue
+	WKTBytesValue
+	WKTFieldMask
+)
+
+// wellKnownTypes maps the fully qualified name of each google.protobuf
+// well-known message type to its WellKnownType constant.
+var wellKnownTypes = map[string]WellKnownType{
+	"google.protobuf.Any":         WKTAny,
+	"google.protobuf.Duration":    WKTDuration,
+	"google.protobuf.Empty":       WKTEmpty,
+	"google.protobuf.Struct":      WKTStruct,
+	"google.protobuf.Timestamp":   WKTTimestamp,
+	"google.protobuf.Value":       WKTValue,
+	"google.protobuf.ListValue":   WKTListValue,
+	"google.protobuf.DoubleValue": WKTDoubleValue,
+	"google.protobuf.FloatValue":  WKTFloatValue,
+	"google.protobuf.Int64Value":  WKTInt64Value,
+	"google.protobuf.UInt64Value": WKTUInt64Value,
+	"google.protobuf.Int32Value":  WKTInt32Value,
+	"google.protobuf.UInt32Value": WKTUInt32Value,
+	"google.protobuf.BoolValue":   WKTBoolValue,
+	"google.protobuf.StringValue": WKTStringValue,
+	"google.protobuf.BytesValue":  WKTBytesValue,
+	"google.protobuf.FieldMask":   WKTFieldMask,
+}
+
+// buildStructTag assembles the comma-separated protobuf struct tag body
+// shared by every FieldType, in the field order protoc-gen-go's
+// internal/encoding/tag.Marshal emits: wire type, field number, cardinality,
+// packed, name, json, proto3, enum, oneof, def. def is always last since its
+// value is not comma-escaped.
+func buildStructTag(ft FieldType, wire, cardinality string, packed bool, enum, def string) string {
+	fld := ft.Field()
+	desc := fld.Descriptor()
+
+	parts := []string{
+		wire,
+		strconv.Itoa(int(desc.GetNumber())),
+		cardinality,
+	}
+
+	if packed {
+		parts = append(parts, "packed")
+	}
+
+	parts = append(parts, "name="+groupOrFieldName(ft))
+
+	if jn := desc.GetJsonName(); jn != "" && jn != desc.GetName() {
+		parts = append(parts, "json="+jn)
+	}
+
+	if !fld.Syntax().SupportsRequiredPrefix() {
+		parts = append(parts, "proto3")
+	}
+
+	if enum != "" {
+		parts = append(parts, "enum="+enum)
+	}
+
+	if desc.OneofIndex != nil && !desc.GetProto3Optional() {
+		parts = append(parts, "oneof")
+	}
+
+	if def != "" {
+		parts = append(parts, "def="+def)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// groupOrFieldName returns the name= token value for a field. Group fields
+// use the original (non-lowercased) name of their group message, since the
+// descriptor's own field name is always a lowercased copy of it; every
+// other field uses its descriptor name directly.
+func groupOrFieldName(ft FieldType) string {
+	if g, ok := ft.(*groupT); ok {
+		return g.msg.Name().String()
+	}
+	return ft.Field().Descriptor().GetName()
+}
+
+// wireTypeTag returns the wire-format token used as the first element of a
+// protobuf struct tag for the given ProtoType.
+func wireTypeTag(pt ProtoType) string {
+	switch pt {
+	case DoubleT, Fixed64T, Sfixed64T:
+		return "fixed64"
+	case FloatT, Fixed32T, Sfixed32T:
+		return "fixed32"
+	case Sint32T:
+		return "zigzag32"
+	case Sint64T:
+		return "zigzag64"
+	case GroupT:
+		return "group"
+	case StringT, BytesT, MessageT:
+		return "bytes"
+	default:
+		return "varint"
+	}
 }
 
-func (m *mapT) IsRepeated() bool   { return false }
-func (m *mapT) IsMap() bool        { return true }
-func (m *mapT) IsSlice() bool      { return false }
-func (m *mapT) Key() FieldTypeElem { return m.key }
+// isPackable reports whether repeated fields of the given ProtoType are
+// eligible for packed encoding.
+func isPackable(pt ProtoType) bool {
+	switch pt {
+	case StringT, BytesT, MessageT, GroupT:
+		return false
+	default:
+		return true
+	}
+}
 
 var (
 	_ FieldType = (*scalarT)(nil)
 	_ FieldType = (*enumT)(nil)
 	_ FieldType = (*embedT)(nil)
+	_ FieldType = (*groupT)(nil)
 	_ FieldType = (*repT)(nil)
 	_ FieldType = (*mapT)(nil)
+	_ FieldType = (*extensionT)(nil)
+
+	_ MapType = (*mapT)(nil)
 )